@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// decodedInsn is one instruction found by the minimal decoder below, used
+// only to give --diff output readable context around a patch site.
+type decodedInsn struct {
+	Offset int
+	Length int
+	Text   string
+}
+
+var regNames8 = [8]string{"eax", "ecx", "edx", "ebx", "esp", "ebp", "esi", "edi"}
+var regNames8Ext = [8]string{"r8d", "r9d", "r10d", "r11d", "r12d", "r13d", "r14d", "r15d"}
+
+// decodeOne decodes a single x86-64 instruction from the start of b,
+// returning its length and a human-readable mnemonic. This only recognizes
+// the handful of instruction shapes this patcher actually touches (getters,
+// parameter loads, and the padding/prologues around them); anything else
+// is reported as a raw byte so the decoder always makes forward progress.
+// For full disassembly, reach for a real decoder such as x86asm; this one
+// exists purely to label the bytes --diff prints.
+func decodeOne(b []byte) (int, string) {
+	if len(b) == 0 {
+		return 0, ""
+	}
+
+	i := 0
+	rex := byte(0)
+	if b[i] >= 0x40 && b[i] <= 0x4F {
+		rex = b[i]
+		i++
+		if i >= len(b) {
+			return i, "(rex prefix)"
+		}
+	}
+
+	switch {
+	case b[i] == 0xCC:
+		return i + 1, "int3"
+	case b[i] == 0xC3:
+		return i + 1, "ret"
+	case b[i] >= 0xB8 && b[i] <= 0xBF:
+		if i+5 > len(b) {
+			return len(b), "(truncated mov)"
+		}
+		reg := regName(b[i]-0xB8, rex&0x01 != 0)
+		imm := binary.LittleEndian.Uint32(b[i+1 : i+5])
+		return i + 5, fmt.Sprintf("mov %s, %d", reg, imm)
+	case b[i] == 0x3D:
+		if i+5 > len(b) {
+			return len(b), "(truncated cmp)"
+		}
+		imm := binary.LittleEndian.Uint32(b[i+1 : i+5])
+		return i + 5, fmt.Sprintf("cmp eax, %d", imm)
+	case rex&0x08 != 0 && b[i] == 0x83 && i+2 < len(b) && b[i+1] == 0xEC:
+		return i + 3, fmt.Sprintf("sub rsp, %d", int8(b[i+2]))
+	default:
+		return i + 1, fmt.Sprintf(".byte 0x%02X", b[i])
+	}
+}
+
+func regName(code byte, extended bool) string {
+	if extended {
+		return regNames8Ext[code]
+	}
+	return regNames8[code]
+}
+
+// decodeInstructions decodes b end-to-end using decodeOne.
+func decodeInstructions(b []byte) []decodedInsn {
+	var insns []decodedInsn
+	offset := 0
+	for offset < len(b) {
+		length, text := decodeOne(b[offset:])
+		if length < 1 {
+			length = 1
+		}
+		insns = append(insns, decodedInsn{Offset: offset, Length: length, Text: text})
+		offset += length
+	}
+	return insns
+}
+
+func formatHex(b []byte) string {
+	var buf bytes.Buffer
+	for i, c := range b {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%02X", c)
+	}
+	return buf.String()
+}
+
+// printUnifiedDiff shows the ±16 bytes of context around a patch, with each
+// changed instruction printed as a before/after pair and unchanged ones
+// printed once, so a reviewer can see exactly what a patch touches without
+// trusting the tool blindly.
+func printUnifiedDiff(original, patched []byte, p Patch) {
+	const context = 16
+	start := p.Offset - context
+	if start < 0 {
+		start = 0
+	}
+	end := p.Offset + len(p.Replacement) + context
+	if end > len(original) {
+		end = len(original)
+	}
+
+	before := original[start:end]
+	after := patched[start:end]
+
+	fmt.Printf("--- %s @ 0x%08X\n", p.Description, p.Offset)
+	for _, insn := range decodeInstructions(before) {
+		absOffset := start + insn.Offset
+		beforeBytes := before[insn.Offset : insn.Offset+insn.Length]
+		afterBytes := after[insn.Offset : insn.Offset+insn.Length]
+
+		if bytes.Equal(beforeBytes, afterBytes) {
+			fmt.Printf("    0x%08X  %-32s %s\n", absOffset, formatHex(beforeBytes), insn.Text)
+			continue
+		}
+
+		_, afterText := decodeOne(afterBytes)
+		fmt.Printf("  - 0x%08X  %-32s %s\n", absOffset, formatHex(beforeBytes), insn.Text)
+		fmt.Printf("  + 0x%08X  %-32s %s\n", absOffset, formatHex(afterBytes), afterText)
+	}
+	fmt.Println()
+}
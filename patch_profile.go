@@ -0,0 +1,164 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+//go:embed profiles/default.json
+var defaultProfileJSON []byte
+
+// Profile is the external, user-editable description of everything this
+// patcher used to carry as package constants: which exe/config to target,
+// what resolution to patch in, and where in the binary to do it.
+type Profile struct {
+	Name       string         `json:"name"`
+	ExeName    string         `json:"exe_name"`
+	ConfigName string         `json:"config_name"`
+	Width      int            `json:"width"`
+	Height     int            `json:"height"`
+	OrigWidth  int            `json:"orig_width"`
+	OrigHeight int            `json:"orig_height"`
+	Patches    []ProfilePatch `json:"patches"`
+}
+
+// ProfilePatch describes a single patch site. Pattern/Section/ByteOffset
+// locate it (see locateSignature); Original and ReplacementTemplate are
+// text/template strings evaluated against the profile's resolution so a
+// profile can target any width/height without recompiling the patcher.
+type ProfilePatch struct {
+	Name                string `json:"name"`
+	Section             string `json:"section"`
+	Pattern             string `json:"pattern"`
+	ByteOffset          int    `json:"byte_offset"`
+	Kind                string `json:"kind"` // "u32", "f32", or "bytes"
+	Original            string `json:"original"`
+	ReplacementTemplate string `json:"replacement_template"`
+	DescriptionTemplate string `json:"description_template"`
+
+	// DetectPatched controls whether isPatched checks this entry's operand
+	// against ReplacementTemplate to decide the exe is already patched. Nil
+	// (the default, unset in JSON) means "yes, use it for detection"; set to
+	// false for patches that aren't reliable already-patched signals (e.g.
+	// ones whose original and replacement values can coincide).
+	DetectPatched *bool `json:"detect_patched,omitempty"`
+}
+
+// resolutionContext is the data made available to a profile's templates.
+type resolutionContext struct {
+	Width       int
+	Height      int
+	OrigWidth   int
+	OrigHeight  int
+	AspectRatio float64
+}
+
+// DefaultProfile returns the profile embedded at build time, describing the
+// patcher's original 3840x2160 → 5120x2880 behavior.
+func DefaultProfile() (*Profile, error) {
+	return parseProfile(defaultProfileJSON)
+}
+
+// LoadProfile reads and parses a profile from disk.
+func LoadProfile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile: %w", err)
+	}
+	return parseProfile(data)
+}
+
+func parseProfile(data []byte) (*Profile, error) {
+	var p Profile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse profile: %w", err)
+	}
+	return &p, nil
+}
+
+func (p *Profile) resolutionContext() resolutionContext {
+	ctx := resolutionContext{
+		Width:      p.Width,
+		Height:     p.Height,
+		OrigWidth:  p.OrigWidth,
+		OrigHeight: p.OrigHeight,
+	}
+	if p.Height != 0 {
+		ctx.AspectRatio = float64(p.Width) / float64(p.Height)
+	}
+	return ctx
+}
+
+// findPatch returns the named patch entry, or nil if the profile doesn't
+// define one by that name.
+func (p *Profile) findPatch(name string) *ProfilePatch {
+	for i := range p.Patches {
+		if p.Patches[i].Name == name {
+			return &p.Patches[i]
+		}
+	}
+	return nil
+}
+
+// render evaluates tmplText against the profile's resolution context.
+func (p *Profile) render(tmplText string) (string, error) {
+	tmpl, err := template.New("profile").Parse(tmplText)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", tmplText, err)
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, p.resolutionContext()); err != nil {
+		return "", fmt.Errorf("failed to render template %q: %w", tmplText, err)
+	}
+	return out.String(), nil
+}
+
+// encodeValue renders a ProfilePatch template and encodes it according to
+// kind: "u32" and "f32" parse the rendered text as a number, "bytes" parses
+// it as space-separated hex (matching the signature pattern syntax).
+func (pp *ProfilePatch) encodeValue(p *Profile, tmplText string) ([]byte, error) {
+	rendered, err := p.render(tmplText)
+	if err != nil {
+		return nil, err
+	}
+
+	switch pp.Kind {
+	case "u32":
+		v, err := strconv.ParseUint(rendered, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q is not a u32: %w", pp.Name, rendered, err)
+		}
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, uint32(v))
+		return b, nil
+	case "f32":
+		v, err := strconv.ParseFloat(rendered, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q is not a f32: %w", pp.Name, rendered, err)
+		}
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, math.Float32bits(float32(v)))
+		return b, nil
+	case "bytes":
+		fields := strings.Fields(rendered)
+		b := make([]byte, len(fields))
+		for i, f := range fields {
+			v, err := strconv.ParseUint(f, 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid byte %q: %w", pp.Name, f, err)
+			}
+			b[i] = byte(v)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("%s: unknown kind %q", pp.Name, pp.Kind)
+	}
+}
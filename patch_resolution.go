@@ -3,32 +3,16 @@ package main
 import (
 	"bufio"
 	"bytes"
-	"encoding/binary"
+	"debug/pe"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
 )
 
-const (
-	exeName      = "HUNDRED_LINE.exe"
-	backupName   = "HUNDRED_LINE.exe.backup"
-	configName   = "userconfig.properties"
-	configBackup = "userconfig.properties.backup"
-
-	targetWidth  = 5120
-	targetHeight = 2880
-
-	origWidth  = 3840
-	origHeight = 2160
-
-	// Offsets in the executable
-	tableOffset        = 0xBDA4F0
-	widthGetterOffset  = 0x054DF7
-	widthParamOffset   = 0x4B4305
-	heightGetterOffset = 0x054D37
-	heightParamOffset  = 0x4B430B
-)
+func backupName(exeName string) string   { return exeName + ".backup" }
+func configBackupName(cfg string) string { return cfg + ".backup" }
 
 type Patch struct {
 	Offset      int
@@ -38,58 +22,142 @@ type Patch struct {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "--self-update" {
+		runSelfUpdate(os.Args[2:])
+		return
+	}
+
+	profilePath := flag.String("profile", "", "path to a patch profile JSON file (default: embedded 5K profile)")
+	exeFlag := flag.String("exe", "", "path to the game executable (default: profile's exe_name)")
+	width := flag.Int("width", 0, "target width override (default: profile's width)")
+	height := flag.Int("height", 0, "target height override (default: profile's height)")
+	forceUnknown := flag.Bool("force-unknown", false, "patch even if the executable doesn't match a known build")
+	dryRun := flag.Bool("dry-run", false, "show what would be patched without writing anything")
+	diff := flag.Bool("diff", false, "with -dry-run or when patching, print a unified hex/disassembly diff of each patch site")
+	flag.Parse()
+
+	profile, err := loadProfile(*profilePath)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		waitForExit()
+		return
+	}
+	if *exeFlag != "" {
+		profile.ExeName = *exeFlag
+	}
+	if *width != 0 {
+		profile.Width = *width
+	}
+	if *height != 0 {
+		profile.Height = *height
+	}
+
 	fmt.Println("╔════════════════════════════════════════════════════════════╗")
 	fmt.Println("║     The Hundred Line - Resolution Patcher                  ║")
-	fmt.Println("║     Patches 3840x2160 → 5120x2880 (5K)                     ║")
+	fmt.Printf("║     Patches %dx%d → %dx%d (%s)\n", profile.OrigWidth, profile.OrigHeight, profile.Width, profile.Height, profile.Name)
 	fmt.Println("╚════════════════════════════════════════════════════════════╝")
 	fmt.Println()
 
 	// Check if exe exists
-	if _, err := os.Stat(exeName); os.IsNotExist(err) {
-		fmt.Printf("ERROR: %s not found!\n", exeName)
+	if _, err := os.Stat(profile.ExeName); os.IsNotExist(err) {
+		fmt.Printf("ERROR: %s not found!\n", profile.ExeName)
 		fmt.Println("Please place this patcher in the same folder as the game executable.")
 		waitForExit()
 		return
 	}
 
 	// Read the executable to check if patched
-	data, err := os.ReadFile(exeName)
+	data, err := os.ReadFile(profile.ExeName)
 	if err != nil {
-		fmt.Printf("ERROR: Failed to read %s: %v\n", exeName, err)
+		fmt.Printf("ERROR: Failed to read %s: %v\n", profile.ExeName, err)
 		waitForExit()
 		return
 	}
 
-	if isPatched(data) {
-		runRestoreMode()
+	if isPatched(data, profile) {
+		if *dryRun {
+			fmt.Println("The executable appears to be patched; a dry run does not restore it.")
+			waitForExit()
+			return
+		}
+		runRestoreMode(profile)
 	} else {
-		runPatchMode(data)
+		if err := verifyVersion(data, *forceUnknown); err != nil {
+			fmt.Printf("ERROR: %v\n", err)
+			waitForExit()
+			return
+		}
+		runPatchMode(data, profile, *dryRun, *diff)
 	}
 }
 
-func isPatched(data []byte) bool {
-	newWidthBytes := make([]byte, 4)
-	binary.LittleEndian.PutUint32(newWidthBytes, targetWidth)
+// loadProfile loads the profile named by path, or the embedded default
+// profile when path is empty.
+func loadProfile(path string) (*Profile, error) {
+	if path == "" {
+		return DefaultProfile()
+	}
+	return LoadProfile(path)
+}
 
-	// Check if the resolution table has the patched value
-	if len(data) > tableOffset+4 {
-		return bytes.Equal(data[tableOffset:tableOffset+4], newWidthBytes)
+// isPatched reports whether data already has the profile's patches applied,
+// by checking whether the byte(s) at each detection-eligible patch's operand
+// already match its replacement value. A patch is detection-eligible unless
+// the profile explicitly opts it out via "detect_patched": false; this lets
+// a profile whose patches don't happen to be named like the embedded default
+// still be detected as already-patched.
+func isPatched(data []byte, profile *Profile) bool {
+	f, err := pe.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return false
 	}
-	return false
+	defer f.Close()
+
+	checked := 0
+	for i := range profile.Patches {
+		pp := &profile.Patches[i]
+		if pp.DetectPatched != nil && !*pp.DetectPatched {
+			continue
+		}
+
+		offset, err := locateProfilePatch(f, data, pp)
+		if err != nil {
+			return false
+		}
+
+		targetBytes, err := pp.encodeValue(profile, pp.ReplacementTemplate)
+		if err != nil {
+			return false
+		}
+		if offset+len(targetBytes) > len(data) {
+			return false
+		}
+		if !bytes.Equal(data[offset:offset+len(targetBytes)], targetBytes) {
+			return false
+		}
+		checked++
+	}
+	return checked > 0
 }
 
-func runPatchMode(data []byte) {
-	fmt.Println("Mode: PATCH")
+func runPatchMode(data []byte, profile *Profile, dryRun, diff bool) {
+	if dryRun {
+		fmt.Println("Mode: PATCH (dry run)")
+	} else {
+		fmt.Println("Mode: PATCH")
+	}
 	fmt.Println()
-	fmt.Printf("This will patch %s to support 5120x2880 resolution.\n", exeName)
-	fmt.Println("A backup will be created automatically.")
+	fmt.Printf("This will patch %s to support %dx%d resolution.\n", profile.ExeName, profile.Width, profile.Height)
+	if !dryRun {
+		fmt.Println("A backup will be created automatically.")
+	}
 	fmt.Println()
 
 	fmt.Printf("File size: %d bytes\n", len(data))
 	fmt.Println()
 
 	// Create patches
-	patches := createPatches(data)
+	patches := createPatches(data, profile)
 	if len(patches) == 0 {
 		fmt.Println("ERROR: No valid patch locations found.")
 		fmt.Println("The executable may be a different version.")
@@ -97,54 +165,67 @@ func runPatchMode(data []byte) {
 		return
 	}
 
+	// Apply patches to an in-memory copy so dry runs and --diff can show the
+	// result without ever touching disk.
+	patchedData := make([]byte, len(data))
+	copy(patchedData, data)
+	for _, p := range patches {
+		copy(patchedData[p.Offset:], p.Replacement)
+	}
+
 	// Display patches
 	fmt.Println("The following changes will be made:")
 	fmt.Println("────────────────────────────────────────────────────────────")
 	for _, p := range patches {
-		origVal := binary.LittleEndian.Uint32(p.Original)
-		newVal := binary.LittleEndian.Uint32(p.Replacement)
-		fmt.Printf("  0x%08X: %d → %d\n", p.Offset, origVal, newVal)
-		fmt.Printf("              %s\n", p.Description)
+		fmt.Printf("  0x%08X: %s\n", p.Offset, p.Description)
 	}
 	fmt.Println("────────────────────────────────────────────────────────────")
 	fmt.Printf("Total patches: %d\n", len(patches))
 	fmt.Println()
-	fmt.Println("userconfig.properties will also be updated.")
+	fmt.Printf("%s will also be updated.\n", profile.ConfigName)
 	fmt.Println()
 
+	if diff {
+		fmt.Println("Unified diff of each patch site:")
+		fmt.Println("────────────────────────────────────────────────────────────")
+		for _, p := range patches {
+			printUnifiedDiff(data, patchedData, p)
+		}
+	}
+
+	if dryRun {
+		fmt.Println("Dry run: no files were modified.")
+		fmt.Println()
+		waitForExit()
+		return
+	}
+
 	// Wait for confirmation
 	fmt.Println("Press ENTER to apply the patch, or close this window to cancel...")
 	waitForEnter()
 
 	// Create backup
-	fmt.Printf("Creating backup: %s\n", backupName)
-	if err := os.WriteFile(backupName, data, 0644); err != nil {
+	backup := backupName(profile.ExeName)
+	fmt.Printf("Creating backup: %s\n", backup)
+	if err := os.WriteFile(backup, data, 0644); err != nil {
 		fmt.Printf("ERROR: Failed to create backup: %v\n", err)
 		waitForExit()
 		return
 	}
 
-	// Apply patches
-	patchedData := make([]byte, len(data))
-	copy(patchedData, data)
-
-	for _, p := range patches {
-		copy(patchedData[p.Offset:], p.Replacement)
-	}
-
 	// Write patched executable
-	if err := os.WriteFile(exeName, patchedData, 0644); err != nil {
+	if err := os.WriteFile(profile.ExeName, patchedData, 0644); err != nil {
 		fmt.Printf("ERROR: Failed to write patched file: %v\n", err)
 		waitForExit()
 		return
 	}
 
 	// Update userconfig.properties
-	if err := patchUserConfig(); err != nil {
-		fmt.Printf("WARNING: Failed to update %s: %v\n", configName, err)
+	if err := patchUserConfig(profile); err != nil {
+		fmt.Printf("WARNING: Failed to update %s: %v\n", profile.ConfigName, err)
 		fmt.Println("You may need to manually set the resolution in the config file.")
 	} else {
-		fmt.Printf("Updated %s\n", configName)
+		fmt.Printf("Updated %s\n", profile.ConfigName)
 	}
 
 	fmt.Println()
@@ -157,23 +238,25 @@ func runPatchMode(data []byte) {
 	waitForExit()
 }
 
-func runRestoreMode() {
+func runRestoreMode(profile *Profile) {
 	fmt.Println("Mode: RESTORE")
 	fmt.Println()
 	fmt.Println("The executable appears to be patched.")
 	fmt.Println("This will restore the original unpatched executable.")
 	fmt.Println()
 
+	backup := backupName(profile.ExeName)
+
 	// Check if backup exists
-	if _, err := os.Stat(backupName); os.IsNotExist(err) {
-		fmt.Printf("ERROR: Backup file not found: %s\n", backupName)
+	if _, err := os.Stat(backup); os.IsNotExist(err) {
+		fmt.Printf("ERROR: Backup file not found: %s\n", backup)
 		fmt.Println("Cannot restore without backup.")
 		waitForExit()
 		return
 	}
 
 	// Verify backup is readable
-	backupData, err := os.ReadFile(backupName)
+	backupData, err := os.ReadFile(backup)
 	if err != nil {
 		fmt.Printf("ERROR: Failed to read backup: %v\n", err)
 		waitForExit()
@@ -188,22 +271,22 @@ func runRestoreMode() {
 	waitForEnter()
 
 	// Restore from backup
-	if err := os.WriteFile(exeName, backupData, 0644); err != nil {
+	if err := os.WriteFile(profile.ExeName, backupData, 0644); err != nil {
 		fmt.Printf("ERROR: Failed to restore executable: %v\n", err)
 		waitForExit()
 		return
 	}
 
 	// Remove exe backup
-	if err := os.Remove(backupName); err != nil {
+	if err := os.Remove(backup); err != nil {
 		fmt.Printf("WARNING: Failed to remove backup file: %v\n", err)
 	}
 
 	// Restore userconfig.properties if backup exists
-	if err := restoreUserConfig(); err != nil {
-		fmt.Printf("WARNING: Failed to restore %s: %v\n", configName, err)
+	if err := restoreUserConfig(profile); err != nil {
+		fmt.Printf("WARNING: Failed to restore %s: %v\n", profile.ConfigName, err)
 	} else {
-		fmt.Printf("Restored %s\n", configName)
+		fmt.Printf("Restored %s\n", profile.ConfigName)
 	}
 
 	fmt.Println()
@@ -216,9 +299,9 @@ func runRestoreMode() {
 	waitForExit()
 }
 
-func patchUserConfig() error {
+func patchUserConfig(profile *Profile) error {
 	// Read existing config
-	configData, err := os.ReadFile(configName)
+	configData, err := os.ReadFile(profile.ConfigName)
 	if err != nil {
 		return fmt.Errorf("failed to read config: %w", err)
 	}
@@ -230,6 +313,7 @@ func patchUserConfig() error {
 	}
 
 	// Backup original config (only if backup doesn't exist)
+	configBackup := configBackupName(profile.ConfigName)
 	if _, err := os.Stat(configBackup); os.IsNotExist(err) {
 		if err := os.WriteFile(configBackup, configData, 0644); err != nil {
 			return fmt.Errorf("failed to create config backup: %w", err)
@@ -238,8 +322,8 @@ func patchUserConfig() error {
 	}
 
 	// Update values (preserving X and Y)
-	config["App.Window.W"] = targetWidth
-	config["App.Window.H"] = targetHeight
+	config["App.Window.W"] = profile.Width
+	config["App.Window.H"] = profile.Height
 	config["App.Window.Mode"] = "BorderlessWindowed"
 
 	// Write updated config with indentation
@@ -248,14 +332,16 @@ func patchUserConfig() error {
 		return fmt.Errorf("failed to encode config: %w", err)
 	}
 
-	if err := os.WriteFile(configName, updatedData, 0644); err != nil {
+	if err := os.WriteFile(profile.ConfigName, updatedData, 0644); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
 
 	return nil
 }
 
-func restoreUserConfig() error {
+func restoreUserConfig(profile *Profile) error {
+	configBackup := configBackupName(profile.ConfigName)
+
 	// Check if config backup exists
 	if _, err := os.Stat(configBackup); os.IsNotExist(err) {
 		return nil // No backup to restore, not an error
@@ -268,7 +354,7 @@ func restoreUserConfig() error {
 	}
 
 	// Restore config
-	if err := os.WriteFile(configName, backupData, 0644); err != nil {
+	if err := os.WriteFile(profile.ConfigName, backupData, 0644); err != nil {
 		return fmt.Errorf("failed to restore config: %w", err)
 	}
 
@@ -280,65 +366,20 @@ func restoreUserConfig() error {
 	return nil
 }
 
-func createPatches(data []byte) []Patch {
-	var patches []Patch
-
-	origWidthBytes := make([]byte, 4)
-	origHeightBytes := make([]byte, 4)
-	newWidthBytes := make([]byte, 4)
-	newHeightBytes := make([]byte, 4)
-
-	binary.LittleEndian.PutUint32(origWidthBytes, origWidth)
-	binary.LittleEndian.PutUint32(origHeightBytes, origHeight)
-	binary.LittleEndian.PutUint32(newWidthBytes, targetWidth)
-	binary.LittleEndian.PutUint32(newHeightBytes, targetHeight)
-
-	// Resolution table patches
-	if len(data) > tableOffset+8 {
-		if bytes.Equal(data[tableOffset:tableOffset+4], origWidthBytes) {
-			patches = append(patches, Patch{
-				Offset:      tableOffset,
-				Original:    origWidthBytes,
-				Replacement: newWidthBytes,
-				Description: fmt.Sprintf("Resolution table: %d → %d (width)", origWidth, targetWidth),
-			})
-		}
-		if bytes.Equal(data[tableOffset+4:tableOffset+8], origHeightBytes) {
-			patches = append(patches, Patch{
-				Offset:      tableOffset + 4,
-				Original:    origHeightBytes,
-				Replacement: newHeightBytes,
-				Description: fmt.Sprintf("Resolution table: %d → %d (height)", origHeight, targetHeight),
-			})
-		}
+func createPatches(data []byte, profile *Profile) []Patch {
+	f, err := pe.NewFile(bytes.NewReader(data))
+	if err != nil {
+		fmt.Printf("ERROR: Failed to parse PE headers: %v\n", err)
+		return nil
 	}
+	defer f.Close()
 
-	// Code patches
-	codePatches := []struct {
-		offset      int
-		original    []byte
-		replacement []byte
-		description string
-	}{
-		{widthGetterOffset, origWidthBytes, newWidthBytes, "Width getter (mov eax, 3840)"},
-		{widthParamOffset, origWidthBytes, newWidthBytes, "Resolution param width (mov edx, 3840)"},
-		{heightGetterOffset, origHeightBytes, newHeightBytes, "Height getter (mov eax, 2160)"},
-		{heightParamOffset, origHeightBytes, newHeightBytes, "Resolution param height (mov r8d, 2160)"},
-	}
-
-	for _, cp := range codePatches {
-		if len(data) > cp.offset+4 && bytes.Equal(data[cp.offset:cp.offset+4], cp.original) {
-			patches = append(patches, Patch{
-				Offset:      cp.offset,
-				Original:    cp.original,
-				Replacement: cp.replacement,
-				Description: cp.description,
-			})
-		} else {
-			fmt.Printf("WARNING: Mismatch at 0x%X, skipping: %s\n", cp.offset, cp.description)
+	var patches []Patch
+	for i := range profile.Patches {
+		if p := patchFromProfileEntry(f, data, profile, &profile.Patches[i]); p != nil {
+			patches = append(patches, *p)
 		}
 	}
-
 	return patches
 }
 
@@ -359,4 +400,5 @@ func init() {
 		dir := filepath.Dir(exe)
 		os.Chdir(dir)
 	}
+	cleanupOldUpdate()
 }
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	currentVersion     = "1.0.0"
+	defaultManifestURL = "https://github.com/jmroon/thl-mods/releases/latest/download/latest.json"
+)
+
+//go:embed selfupdate/update_key.pub.hex
+var updatePublicKeyHex string
+
+// updateManifest mirrors the latest.json file published alongside a
+// release: what version it is, where to get it, and how to verify it.
+type updateManifest struct {
+	Version           string `json:"version"`
+	URL               string `json:"url"`
+	SHA256            string `json:"sha256"`
+	MinisignSignature string `json:"minisign_signature"`
+}
+
+// runSelfUpdate handles the `--self-update` subcommand.
+func runSelfUpdate(args []string) {
+	fs := flag.NewFlagSet("self-update", flag.ExitOnError)
+	manifestURL := fs.String("url", defaultManifestURL, "URL of the latest.json update manifest")
+	allowDowngrade := fs.Bool("allow-downgrade", false, "install even if the manifest version isn't newer than the current one")
+	fs.Parse(args)
+
+	fmt.Println("Mode: SELF-UPDATE")
+	fmt.Println()
+	fmt.Printf("Current version: %s\n", currentVersion)
+	fmt.Printf("Checking: %s\n", *manifestURL)
+	fmt.Println()
+
+	if err := selfUpdate(*manifestURL, *allowDowngrade); err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		waitForExit()
+		return
+	}
+	waitForExit()
+}
+
+func selfUpdate(manifestURL string, allowDowngrade bool) error {
+	manifest, err := fetchManifest(manifestURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch update manifest: %w", err)
+	}
+
+	cmp, err := compareVersions(manifest.Version, currentVersion)
+	if err != nil {
+		return fmt.Errorf("failed to compare versions: %w", err)
+	}
+	if cmp <= 0 {
+		if !allowDowngrade {
+			if cmp == 0 {
+				fmt.Println("Already up to date.")
+				return nil
+			}
+			return fmt.Errorf("manifest version %s is not newer than the current version %s (rerun with --allow-downgrade to install it anyway)", manifest.Version, currentVersion)
+		}
+		fmt.Printf("WARNING: Installing %s over %s because --allow-downgrade was set.\n", manifest.Version, currentVersion)
+	} else {
+		fmt.Printf("New version available: %s\n", manifest.Version)
+	}
+
+	newBinary, err := downloadFile(manifest.URL)
+	if err != nil {
+		return fmt.Errorf("failed to download update: %w", err)
+	}
+
+	if err := verifyUpdate(newBinary, manifest); err != nil {
+		return fmt.Errorf("update verification failed: %w", err)
+	}
+	fmt.Println("Checksum and signature verified.")
+	fmt.Println()
+
+	fmt.Println("Press ENTER to install the update, or close this window to cancel...")
+	waitForEnter()
+
+	if err := installUpdate(newBinary); err != nil {
+		return fmt.Errorf("failed to install update: %w", err)
+	}
+
+	fmt.Printf("Updated to version %s. Re-run the patcher to use it.\n", manifest.Version)
+	return nil
+}
+
+// compareVersions compares two dotted numeric version strings (e.g.
+// "1.2.0"), returning >0 if a is newer than b, <0 if older, and 0 if equal.
+// Missing trailing components are treated as 0, so "1.2" == "1.2.0".
+func compareVersions(a, b string) (int, error) {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	n := len(aParts)
+	if len(bParts) > n {
+		n = len(bParts)
+	}
+	for i := 0; i < n; i++ {
+		var av, bv int
+		var err error
+		if i < len(aParts) {
+			if av, err = strconv.Atoi(aParts[i]); err != nil {
+				return 0, fmt.Errorf("invalid version %q", a)
+			}
+		}
+		if i < len(bParts) {
+			if bv, err = strconv.Atoi(bParts[i]); err != nil {
+				return 0, fmt.Errorf("invalid version %q", b)
+			}
+		}
+		if av != bv {
+			return av - bv, nil
+		}
+	}
+	return 0, nil
+}
+
+func fetchManifest(url string) (*updateManifest, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var m updateManifest
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &m, nil
+}
+
+func downloadFile(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// verifyUpdate checks the downloaded binary's SHA-256 against the manifest
+// and verifies a detached Ed25519 signature over it against the embedded
+// public key. The manifest names the field "minisign_signature" after the
+// tool used to produce it, but this only verifies a plain detached Ed25519
+// signature, not the full minisign envelope format.
+func verifyUpdate(data []byte, manifest *updateManifest) error {
+	sum := sha256.Sum256(data)
+	gotSHA := hex.EncodeToString(sum[:])
+	if gotSHA != manifest.SHA256 {
+		return fmt.Errorf("SHA-256 mismatch: got %s, manifest says %s", gotSHA, manifest.SHA256)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(manifest.MinisignSignature)
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+
+	pubKey, err := hex.DecodeString(strings.TrimSpace(updatePublicKeyHex))
+	if err != nil || len(pubKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("embedded update public key is malformed")
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(pubKey), data, sig) {
+		return fmt.Errorf("signature does not match embedded public key")
+	}
+	return nil
+}
+
+// installUpdate atomically swaps the running executable for data. On
+// Windows the running exe can't be overwritten while it's mapped, so it's
+// renamed aside to a ".old" sibling first, the new binary is moved into
+// place, and the ".old" file is removed afterward (or left for
+// cleanupOldUpdate to remove on the next launch, if it's still locked).
+func installUpdate(data []byte) error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate running executable: %w", err)
+	}
+
+	dir := filepath.Dir(exePath)
+	tmpPath := filepath.Join(dir, filepath.Base(exePath)+".new")
+	oldPath := exePath + ".old"
+
+	if err := os.WriteFile(tmpPath, data, 0755); err != nil {
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+
+	if runtime.GOOS == "windows" {
+		if err := os.Rename(exePath, oldPath); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to move running executable aside: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmpPath, exePath); err != nil {
+		return fmt.Errorf("failed to move new binary into place: %w", err)
+	}
+
+	if runtime.GOOS == "windows" {
+		if err := os.Remove(oldPath); err != nil {
+			fmt.Printf("WARNING: Failed to remove %s, it will be cleaned up on next launch: %v\n", oldPath, err)
+		}
+	}
+
+	return nil
+}
+
+// cleanupOldUpdate removes a ".old" executable left behind by a previous
+// self-update that couldn't delete it while Windows still had it locked.
+func cleanupOldUpdate() {
+	exePath, err := os.Executable()
+	if err != nil {
+		return
+	}
+	os.Remove(exePath + ".old")
+}
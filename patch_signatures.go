@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"debug/pe"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// signature locates exactly one byte sequence inside a named PE section.
+// Bytes in pattern where mask is 0 are wildcards and match anything; this
+// lets a match survive the game shifting unrelated code around while the
+// immediate values we care about change underneath it.
+type signature struct {
+	name    string
+	section string
+	pattern []byte
+	mask    []byte
+}
+
+// newSignature parses a space-separated hex pattern such as
+// "B8 ?? ?? ?? ?? C3" ("??" = wildcard byte) into a signature.
+func newSignature(name, section, hexPattern string) (signature, error) {
+	fields := strings.Fields(hexPattern)
+	pattern := make([]byte, len(fields))
+	mask := make([]byte, len(fields))
+	for i, f := range fields {
+		if f == "??" {
+			continue
+		}
+		b, err := strconv.ParseUint(f, 16, 8)
+		if err != nil {
+			return signature{}, fmt.Errorf("signature %s: invalid pattern byte %q: %w", name, f, err)
+		}
+		pattern[i] = byte(b)
+		mask[i] = 0xFF
+	}
+	return signature{name: name, section: section, pattern: pattern, mask: mask}, nil
+}
+
+// locateSignature scans the section named by sig.section for sig.pattern and
+// returns the file offset of the single match. It is an error for a
+// signature to match zero or more than once: either means the game build no
+// longer looks like what this patcher expects.
+func locateSignature(f *pe.File, data []byte, sig signature) (int, error) {
+	sec := f.Section(sig.section)
+	if sec == nil {
+		return 0, fmt.Errorf("%s: section %s not present in executable", sig.name, sig.section)
+	}
+
+	start := int(sec.Offset)
+	end := start + int(sec.Size)
+	if end > len(data) {
+		end = len(data)
+	}
+
+	var matches []int
+	for i := start; i+len(sig.pattern) <= end; i++ {
+		if matchAt(data[i:i+len(sig.pattern)], sig.pattern, sig.mask) {
+			matches = append(matches, i)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("%s: signature not found in %s (game version may have changed)", sig.name, sig.section)
+	case 1:
+		return matches[0], nil
+	default:
+		return 0, fmt.Errorf("%s: signature matched %d times in %s, expected exactly 1", sig.name, len(matches), sig.section)
+	}
+}
+
+func matchAt(data, pattern, mask []byte) bool {
+	for i := range pattern {
+		if mask[i] != 0 && data[i] != pattern[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// locateProfilePatch resolves where in data a profile patch's signature
+// matches, returning the file offset of its operand (match offset +
+// ByteOffset).
+func locateProfilePatch(f *pe.File, data []byte, pp *ProfilePatch) (int, error) {
+	sig, err := newSignature(pp.Name, pp.Section, pp.Pattern)
+	if err != nil {
+		return 0, err
+	}
+
+	matchOffset, err := locateSignature(f, data, sig)
+	if err != nil {
+		return 0, err
+	}
+
+	offset := matchOffset + pp.ByteOffset
+	if offset < 0 {
+		return 0, fmt.Errorf("%s: byte_offset %d resolves to a negative offset from match at 0x%X", pp.Name, pp.ByteOffset, matchOffset)
+	}
+	return offset, nil
+}
+
+// patchFromProfileEntry resolves a single profile patch entry against data
+// and returns its Patch, or nil (with a printed warning) if the signature
+// didn't resolve or the bytes at the operand no longer match what the
+// profile expects to find there.
+func patchFromProfileEntry(f *pe.File, data []byte, profile *Profile, pp *ProfilePatch) *Patch {
+	offset, err := locateProfilePatch(f, data, pp)
+	if err != nil {
+		fmt.Printf("WARNING: %v\n", err)
+		return nil
+	}
+
+	origBytes, err := pp.encodeValue(profile, pp.Original)
+	if err != nil {
+		fmt.Printf("WARNING: %s: %v\n", pp.Name, err)
+		return nil
+	}
+	if offset+len(origBytes) > len(data) {
+		fmt.Printf("WARNING: %s: operand out of range, skipping\n", pp.Name)
+		return nil
+	}
+	if !bytes.Equal(data[offset:offset+len(origBytes)], origBytes) {
+		fmt.Printf("WARNING: Mismatch at 0x%X, skipping: %s\n", offset, pp.Name)
+		return nil
+	}
+
+	replacementBytes, err := pp.encodeValue(profile, pp.ReplacementTemplate)
+	if err != nil {
+		fmt.Printf("WARNING: %s: %v\n", pp.Name, err)
+		return nil
+	}
+
+	description, err := profile.render(pp.DescriptionTemplate)
+	if err != nil {
+		description = pp.Name
+	}
+
+	return &Patch{
+		Offset:      offset,
+		Original:    origBytes,
+		Replacement: replacementBytes,
+		Description: description,
+	}
+}
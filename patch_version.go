@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"debug/pe"
+	"encoding/hex"
+	"fmt"
+	"time"
+	"unicode/utf16"
+)
+
+// knownBuild identifies a specific shipped build of the game by the SHA-256
+// of the whole file and of just its .text section. A build is recognized if
+// either hash matches, so the .text hash still identifies a build whose
+// full-file hash differs only outside its code (e.g. in its resources).
+type knownBuild struct {
+	Version    string
+	FullSHA256 string
+	TextSHA256 string
+}
+
+// knownBuilds is a small, manually curated table of verified builds. Add an
+// entry here (via `sha256sum HUNDRED_LINE.exe` and the .text hash reported
+// by --force-unknown output) whenever a new build is confirmed to work.
+//
+// TODO(release): the entries below are UNVERIFIED PLACEHOLDERS, not hashes
+// of any real HUNDRED_LINE.exe build — nobody has run sha256sum against an
+// actual Steam/GOG copy yet. Until they're replaced with real captured
+// hashes, every real user's exe will fail to match and --force-unknown will
+// be required on every run. Do not ship this table as-is.
+var knownBuilds = []knownBuild{
+	{
+		Version:    "Steam 1.0.2 (placeholder, unverified)",
+		FullSHA256: "a94a8fe5ccb19ba61c4c0873d391e987982fbbd3b0d1c2dce69b1f4c6e3d2a1a",
+		TextSHA256: "3c6e0b8a9c15224a8228b9a98ca1531d3e3f8a1b4f7c2d9e5a6b3c0d1e2f3a4b",
+	},
+	{
+		Version:    "GOG 1.1.0 (placeholder, unverified)",
+		FullSHA256: "d4735e3a265e16eee03f59718b9b5d03019c07d8b6c51f90da3a666eec13ab3",
+		TextSHA256: "7b52009b64fd0a2a49e6d8a939753077792b0554bff13c8b3b75198e6c1e9d6",
+	},
+}
+
+// fingerprint is everything we can learn about an exe without patching it.
+type fingerprint struct {
+	FullSHA256  string
+	TextSHA256  string
+	Timestamp   time.Time
+	VersionInfo map[string]string
+	Known       string // matching knownBuild.Version, or "" if unrecognized
+}
+
+// fingerprintExecutable hashes the full file and its .text section and
+// pulls the PE timestamp and any VERSIONINFO strings it can find.
+func fingerprintExecutable(data []byte) (*fingerprint, error) {
+	f, err := pe.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PE headers: %w", err)
+	}
+	defer f.Close()
+
+	textSec := f.Section(".text")
+	if textSec == nil {
+		return nil, fmt.Errorf(".text section not present in executable")
+	}
+	textData, err := textSec.Data()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .text section: %w", err)
+	}
+
+	fullSum := sha256.Sum256(data)
+	textSum := sha256.Sum256(textData)
+
+	fp := &fingerprint{
+		FullSHA256:  hex.EncodeToString(fullSum[:]),
+		TextSHA256:  hex.EncodeToString(textSum[:]),
+		Timestamp:   time.Unix(int64(f.FileHeader.TimeDateStamp), 0).UTC(),
+		VersionInfo: findVersionInfoStrings(f),
+	}
+
+	for _, kb := range knownBuilds {
+		fullMatch := kb.FullSHA256 == fp.FullSHA256
+		textMatch := kb.TextSHA256 == fp.TextSHA256
+		if !fullMatch && !textMatch {
+			continue
+		}
+		fp.Known = kb.Version
+		if !fullMatch {
+			fmt.Printf("NOTE: %s matched by .text section hash; the full-file hash differs from the known-good value.\n", kb.Version)
+		}
+		break
+	}
+
+	return fp, nil
+}
+
+// versionInfoKeys are the VS_VERSIONINFO string table entries we look for.
+var versionInfoKeys = []string{
+	"CompanyName", "FileDescription", "FileVersion",
+	"ProductName", "ProductVersion", "LegalCopyright",
+}
+
+// findVersionInfoStrings does a best-effort scan of the .rsrc section for
+// VS_VERSIONINFO string table entries. It isn't a full resource-tree parser
+// (PE version resources are a deeply nested, loosely-documented structure);
+// it just looks for each known key's UTF-16LE bytes and reads the
+// null-terminated value that follows it, which is enough to show a human
+// the build info without a full parser.
+func findVersionInfoStrings(f *pe.File) map[string]string {
+	sec := f.Section(".rsrc")
+	if sec == nil {
+		return nil
+	}
+	raw, err := sec.Data()
+	if err != nil {
+		return nil
+	}
+
+	result := map[string]string{}
+	for _, key := range versionInfoKeys {
+		if v, ok := findUTF16Value(raw, key); ok {
+			result[key] = v
+		}
+	}
+	return result
+}
+
+func findUTF16Value(raw []byte, key string) (string, bool) {
+	needle := utf16leBytes(key)
+	idx := bytes.Index(raw, needle)
+	if idx < 0 {
+		return "", false
+	}
+	pos := idx + len(needle)
+
+	// In a VERSIONINFO String entry the key is followed by its 2-byte null
+	// terminator and then padding to a 4-byte boundary before the value
+	// string begins.
+	pos += 2
+	for pos < len(raw) && pos%4 != 0 {
+		pos++
+	}
+
+	start := pos
+	for pos+1 < len(raw) {
+		if raw[pos] == 0 && raw[pos+1] == 0 {
+			break
+		}
+		pos += 2
+	}
+	if pos <= start {
+		return "", false
+	}
+	return utf16leToString(raw[start:pos]), true
+}
+
+func utf16leBytes(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	out := make([]byte, 0, len(units)*2)
+	for _, u := range units {
+		out = append(out, byte(u), byte(u>>8))
+	}
+	return out
+}
+
+func utf16leToString(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i]) | uint16(b[2*i+1])<<8
+	}
+	return string(utf16.Decode(units))
+}
+
+// verifyVersion fingerprints data and refuses to proceed unless it matches
+// a known build, unless forceUnknown is set.
+func verifyVersion(data []byte, forceUnknown bool) error {
+	fp, err := fingerprintExecutable(data)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Version check:")
+	fmt.Printf("  Full file SHA-256: %s\n", fp.FullSHA256)
+	fmt.Printf("  .text SHA-256:     %s\n", fp.TextSHA256)
+	fmt.Printf("  PE timestamp:      %s\n", fp.Timestamp.Format(time.RFC1123))
+	if v, ok := fp.VersionInfo["ProductVersion"]; ok {
+		fmt.Printf("  Product version:   %s\n", v)
+	}
+	if v, ok := fp.VersionInfo["FileVersion"]; ok {
+		fmt.Printf("  File version:      %s\n", v)
+	}
+
+	if fp.Known != "" {
+		fmt.Printf("Detected build: %s\n", fp.Known)
+		fmt.Println()
+		return nil
+	}
+
+	fmt.Println()
+	if !forceUnknown {
+		return fmt.Errorf("executable does not match any known build\nrerun with --force-unknown to patch it anyway (at your own risk)")
+	}
+
+	fmt.Println("WARNING: Proceeding with an unrecognized build because --force-unknown was set.")
+	fmt.Println()
+	return nil
+}